@@ -0,0 +1,416 @@
+package fracdex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// byteHeadMid is the pivot value for the single head byte used by the
+// *Bytes functions: heads above it encode a positive integer part, heads
+// below it encode a negative one, and the magnitude of the integer part's
+// length grows symmetrically as the head moves away from the pivot. The
+// pivot byte itself is never a valid head.
+const byteHeadMid = 0x80
+
+// byteMaxDigit is the largest valid digit byte. Digits run from 1 to
+// byteMaxDigit; 0x00 is never a digit. It appears in an order key only as
+// the head byte of the longest negative integer parts (see byteSmallestInt),
+// never after a key's first byte.
+const byteMaxDigit = 0xff
+
+var byteZero = []byte{byteHeadMid + 1, 1}
+var byteSmallestInt = append([]byte{0}, bytes.Repeat([]byte{1}, byteHeadMid)...)
+
+// KeyBetweenBytes is the byte-slice counterpart of KeyBetween: it returns a
+// key that sorts (per bytes.Compare) between a and b, using the full
+// 0x01-0xff range as digits instead of a printable alphabet. This avoids
+// the ~40% size expansion of a base62 string when keys are stored as BLOBs,
+// and lets a single midpoint step insert many more items before the
+// fractional part needs to grow.
+//
+// Either a or b can be nil/empty. If a is empty it indicates the smallest
+// key, if b is empty it indicates the largest key. b must be empty or > a.
+func KeyBetweenBytes(a, b []byte) ([]byte, error) {
+	if len(a) > 0 {
+		if err := validateByteOrderKey(a); err != nil {
+			return nil, err
+		}
+	}
+	if len(b) > 0 {
+		if err := validateByteOrderKey(b); err != nil {
+			return nil, err
+		}
+	}
+	if len(a) > 0 && len(b) > 0 && bytes.Compare(a, b) >= 0 {
+		return nil, fmt.Errorf("%x >= %x", a, b)
+	}
+
+	if len(a) == 0 {
+		if len(b) == 0 {
+			return byteZero, nil
+		}
+
+		ib, err := getBytesIntPart(b)
+		if err != nil {
+			return nil, err
+		}
+		fb := b[len(ib):]
+		if bytes.Equal(ib, byteSmallestInt) {
+			return append(ib, midpointBytes(nil, fb)...), nil
+		}
+		if bytes.Compare(ib, b) < 0 {
+			return ib, nil
+		}
+		res, err := decrementIntBytes(ib)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			return nil, errors.New("range underflow")
+		}
+		return res, nil
+	}
+
+	if len(b) == 0 {
+		ia, err := getBytesIntPart(a)
+		if err != nil {
+			return nil, err
+		}
+		fa := a[len(ia):]
+		i, err := incrementIntBytes(ia)
+		if err != nil {
+			return nil, err
+		}
+		if i == nil {
+			return append(ia, midpointBytes(fa, nil)...), nil
+		}
+		return i, nil
+	}
+
+	ia, err := getBytesIntPart(a)
+	if err != nil {
+		return nil, err
+	}
+	fa := a[len(ia):]
+	ib, err := getBytesIntPart(b)
+	if err != nil {
+		return nil, err
+	}
+	fb := b[len(ib):]
+	if bytes.Equal(ia, ib) {
+		return append(ia, midpointBytes(fa, fb)...), nil
+	}
+	i, err := incrementIntBytes(ia)
+	if err != nil {
+		return nil, err
+	}
+	if i == nil {
+		return nil, errors.New("range overflow")
+	}
+	if bytes.Compare(i, b) < 0 {
+		return i, nil
+	}
+	return append(ia, midpointBytes(fa, nil)...), nil
+}
+
+// NKeysBetweenBytes is the byte-slice counterpart of NKeysBetween: it
+// returns n keys that sort (per bytes.Compare) between a and b.
+func NKeysBetweenBytes(a, b []byte, n uint) ([][]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if n == 1 {
+		k, err := KeyBetweenBytes(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{k}, nil
+	}
+	if len(b) == 0 {
+		k, err := KeyBetweenBytes(a, nil)
+		if err != nil {
+			return nil, err
+		}
+		result := make([][]byte, n)
+		result[0] = k
+		for i := uint(1); i < n; i++ {
+			k, err = KeyBetweenBytes(k, nil)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = k
+		}
+		return result, nil
+	}
+	if len(a) == 0 {
+		k, err := KeyBetweenBytes(nil, b)
+		if err != nil {
+			return nil, err
+		}
+		result := make([][]byte, n)
+		result[n-1] = k
+		for i := int(n) - 2; i >= 0; i-- {
+			k, err = KeyBetweenBytes(nil, k)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = k
+		}
+		return result, nil
+	}
+
+	mid := n / 2
+	k, err := KeyBetweenBytes(a, b)
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]byte, n)
+	result[mid] = k
+
+	lower, err := NKeysBetweenBytes(a, k, mid)
+	if err != nil {
+		return nil, err
+	}
+	copy(result, lower)
+
+	upper, err := NKeysBetweenBytes(k, b, n-mid-1)
+	if err != nil {
+		return nil, err
+	}
+	copy(result[mid+1:], upper)
+
+	return result, nil
+}
+
+// `a < b` per bytes.Compare if `b` is non-empty.
+// a == nil means first possible key.
+// b == nil means last possible key.
+func midpointBytes(a, b []byte) []byte {
+	if len(b) > 0 {
+		// remove longest common prefix, padding `a` with the zero digit (1)
+		// past its own length if needed, as Codec.midpoint does.
+		i := 0
+		for ; i < len(b); i++ {
+			d := byte(1)
+			if i < len(a) {
+				d = a[i]
+			}
+			if d != b[i] {
+				break
+			}
+		}
+		if i > 0 {
+			var aRest []byte
+			if i < len(a) {
+				aRest = a[i:]
+			}
+			out := append([]byte{}, b[0:i]...)
+			return append(out, midpointBytes(aRest, b[i:])...)
+		}
+	}
+
+	// first digits (or lack of digit) are different
+	digitA := 0
+	if len(a) > 0 {
+		digitA = int(a[0]) - 1
+	}
+	digitB := byteMaxDigit
+	if len(b) > 0 {
+		digitB = int(b[0]) - 1
+	}
+	if digitB-digitA > 1 {
+		midDigit := (digitA + digitB + 1) / 2
+		return []byte{byte(midDigit + 1)}
+	}
+
+	// first digits are consecutive
+	if len(b) > 1 {
+		return []byte{b[0]}
+	}
+
+	// `b` is empty or has length 1 (a single digit).
+	sa := []byte{}
+	if len(a) > 0 {
+		sa = a[1:]
+	}
+	return append([]byte{byte(digitA + 1)}, midpointBytes(sa, nil)...)
+}
+
+func validateIntBytes(x []byte) error {
+	exp, err := getByteIntLen(x[0])
+	if err != nil {
+		return err
+	}
+	if len(x) != exp {
+		return fmt.Errorf("invalid integer part of order key: %x", x)
+	}
+	return nil
+}
+
+func getByteIntLen(head byte) (int, error) {
+	if head > byteHeadMid {
+		return int(head) - byteHeadMid + 1, nil
+	}
+	if head < byteHeadMid {
+		return byteHeadMid - int(head) + 1, nil
+	}
+	return 0, fmt.Errorf("invalid order key head byte: 0x%02x", head)
+}
+
+func getBytesIntPart(key []byte) ([]byte, error) {
+	intPartLen, err := getByteIntLen(key[0])
+	if err != nil {
+		return nil, err
+	}
+	if intPartLen > len(key) {
+		return nil, fmt.Errorf("invalid order key: %x", key)
+	}
+	return key[0:intPartLen], nil
+}
+
+func validateByteOrderKey(key []byte) error {
+	if bytes.Equal(key, byteSmallestInt) {
+		return fmt.Errorf("invalid order key: %x", key)
+	}
+	i, err := getBytesIntPart(key)
+	if err != nil {
+		return err
+	}
+	f := key[len(i):]
+	if len(f) > 0 && f[len(f)-1] == 1 {
+		return fmt.Errorf("invalid order key: %x", key)
+	}
+	return nil
+}
+
+// returns nil, nil if x is the largest representable integer part
+func incrementIntBytes(x []byte) ([]byte, error) {
+	if err := validateIntBytes(x); err != nil {
+		return nil, err
+	}
+	head := x[0]
+	digs := append([]byte{}, x[1:]...)
+	carry := true
+	for i := len(digs) - 1; carry && i >= 0; i-- {
+		if digs[i] == byteMaxDigit {
+			digs[i] = 1
+		} else {
+			digs[i]++
+			carry = false
+		}
+	}
+	if !carry {
+		return append([]byte{head}, digs...), nil
+	}
+
+	if head > byteHeadMid {
+		if head == byteMaxDigit {
+			// already the longest possible positive integer part
+			return nil, nil
+		}
+		digs = append(digs, 1)
+		return append([]byte{head + 1}, digs...), nil
+	}
+
+	// head < byteHeadMid
+	if head == byteHeadMid-1 {
+		// cross over into the smallest positive integer part
+		return append([]byte{}, byteZero...), nil
+	}
+	digs = digs[1:]
+	return append([]byte{head + 1}, digs...), nil
+}
+
+// returns nil, nil if x is the smallest representable integer part
+func decrementIntBytes(x []byte) ([]byte, error) {
+	if err := validateIntBytes(x); err != nil {
+		return nil, err
+	}
+	head := x[0]
+	digs := append([]byte{}, x[1:]...)
+	borrow := true
+	for i := len(digs) - 1; borrow && i >= 0; i-- {
+		if digs[i] == 1 {
+			digs[i] = byteMaxDigit
+		} else {
+			digs[i]--
+			borrow = false
+		}
+	}
+	if !borrow {
+		return append([]byte{head}, digs...), nil
+	}
+
+	if head < byteHeadMid {
+		if head == 0 {
+			// already the most negative (longest) integer part
+			return nil, nil
+		}
+		digs = append(digs, byteMaxDigit)
+		return append([]byte{head - 1}, digs...), nil
+	}
+
+	// head > byteHeadMid
+	if head == byteHeadMid+1 {
+		// cross over into the shortest negative integer part
+		return []byte{byteHeadMid - 1, byteMaxDigit}, nil
+	}
+	digs = digs[1:]
+	return append([]byte{head - 1}, digs...), nil
+}
+
+// EncodeBase62 encodes a byte order key (as produced by KeyBetweenBytes) as
+// a base62 string, so it can travel through systems that only accept text.
+// It does not preserve sort order; it is meant for transport and storage,
+// with DecodeBase62 recovering the exact original bytes on the other end.
+func EncodeBase62(key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	// Prepend a sentinel byte so that leading 0x00 bytes in key (which are
+	// structurally valid - see byteSmallestInt and byteHeadMid) survive
+	// the round trip instead of being dropped as insignificant leading
+	// zeros of the big-endian integer.
+	padded := make([]byte, len(key)+1)
+	padded[0] = 1
+	copy(padded[1:], key)
+	n := new(big.Int).SetBytes(padded)
+
+	base := big.NewInt(int64(len(base62Digits)))
+	mod := new(big.Int)
+	var digits []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		digits = append(digits, base62Digits[mod.Int64()])
+	}
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
+
+// DecodeBase62 reverses EncodeBase62, returning the exact byte order key
+// that was encoded.
+func DecodeBase62(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	n := new(big.Int)
+	base := big.NewInt(int64(len(base62Digits)))
+	for i := 0; i < len(s); i++ {
+		idx := strings.IndexByte(base62Digits, s[i])
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid base62 character: %q", s[i])
+		}
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(idx)))
+	}
+	raw := n.Bytes()
+	if len(raw) == 0 || raw[0] != 1 {
+		return nil, fmt.Errorf("invalid base62-encoded order key: %q", s)
+	}
+	return raw[1:], nil
+}
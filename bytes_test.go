@@ -0,0 +1,107 @@
+package fracdex
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	test := func(a, b, exp []byte) {
+		act, err := KeyBetweenBytes(a, b)
+		if err != nil {
+			assert.Nil(act)
+			assert.Equal(string(exp), err.Error())
+		} else {
+			assert.Nil(err)
+			assert.Equal(exp, act)
+		}
+	}
+
+	test(nil, nil, []byte{0x81, 0x01})
+	test(nil, []byte{0x81, 0x01}, []byte{0x7f, 0xff})
+	test([]byte{0x81, 0x01}, nil, []byte{0x81, 0x02})
+	test([]byte{0x81, 0x01}, []byte{0x81, 0x02}, []byte{0x81, 0x01, 0x81})
+	test(nil, []byte{0x81, 0x01}, []byte{0x7f, 0xff})
+
+	// a >= b is rejected just like in the string API.
+	_, err := KeyBetweenBytes([]byte{0x81, 0x02}, []byte{0x81, 0x01})
+	assert.Error(err)
+
+	// the reserved sentinel can't be used as a neighbor.
+	_, err = KeyBetweenBytes(nil, byteSmallestInt)
+	assert.Error(err)
+}
+
+func TestNKeysBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	ks, err := NKeysBetweenBytes(nil, nil, 5)
+	assert.Nil(err)
+	assert.Equal([][]byte{
+		{0x81, 0x01},
+		{0x81, 0x02},
+		{0x81, 0x03},
+		{0x81, 0x04},
+		{0x81, 0x05},
+	}, ks)
+
+	a, err := KeyBetweenBytes(nil, nil)
+	assert.Nil(err)
+	ks, err = NKeysBetweenBytes(a, nil, 5)
+	assert.Nil(err)
+	assert.Equal([][]byte{
+		{0x81, 0x02},
+		{0x81, 0x03},
+		{0x81, 0x04},
+		{0x81, 0x05},
+		{0x81, 0x06},
+	}, ks)
+
+	for i := 1; i < len(ks); i++ {
+		assert.True(bytes.Compare(ks[i-1], ks[i]) < 0)
+	}
+}
+
+func TestBase62EncodeDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := [][]byte{
+		nil,
+		{1},
+		{0x81, 1},
+		{0, 1, 1, 1},
+		{0xff, 0xff, 0xff},
+	}
+	for _, c := range cases {
+		s := EncodeBase62(c)
+		back, err := DecodeBase62(s)
+		assert.Nil(err)
+		if len(c) == 0 {
+			assert.Len(back, 0)
+		} else {
+			assert.Equal(c, back)
+		}
+	}
+
+	_, err := DecodeBase62("not valid base62!")
+	assert.Error(err)
+}
+
+func TestKeyBetweenBytesSortsWithNeighbors(t *testing.T) {
+	assert := assert.New(t)
+
+	prev := []byte(nil)
+	for i := 0; i < 50; i++ {
+		k, err := KeyBetweenBytes(prev, nil)
+		assert.Nil(err)
+		if prev != nil {
+			assert.True(bytes.Compare(prev, k) < 0, fmt.Sprintf("%x should sort before %x", prev, k))
+		}
+		prev = k
+	}
+}
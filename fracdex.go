@@ -7,23 +7,149 @@ import (
 	"strings"
 )
 
+// base62Digits is the default digit alphabet: the same one used by the
+// original implementation of this package.
 const base62Digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-const smallestInt = "A00000000000000000000000000"
-const zero = "a0"
+
+// base64Digits is a URL-safe base64 alphabet (RFC 4648 section 5), sorted
+// into ASCII order as Codec requires. It has no '/' so it's safe to embed
+// in URLs and filenames, and its larger digit set produces shorter keys
+// and denser midpoints than base62Digits.
+const base64Digits = "-0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"
+
+const headPosDigits = "abcdefghijklmnopqrstuvwxyz"
+const headNegDigits = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Codec encodes fractional order keys over a particular digit alphabet. It
+// bundles the alphabet together with the two "head" ranges used to encode
+// the length of a key's integer part: headPos for keys with a positive
+// (zero or greater) integer part, and headNeg for keys with a negative
+// one. Use NewCodec to build a Codec for a custom alphabet, or one of the
+// ready-made Codecs (Base62, Base64) for a common one.
+//
+// The zero Codec is not valid; always construct one with NewCodec.
+type Codec struct {
+	digits  string
+	headPos string
+	headNeg string
+
+	smallestInt string
+	zero        string
+}
+
+// NewCodec builds a Codec from a digit alphabet and the two head ranges
+// used to encode the length of a key's integer part. digits must have at
+// least two characters and headPos and headNeg must be non-empty; all three
+// must be strictly ASCII-sorted (no repeated or
+// out-of-order characters), and headPos/headNeg must not share any
+// characters with each other, since the head character is what tells
+// KeyBetween which range applies, and so how to decode the rest of the key.
+//
+// digits is free to reuse characters from headPos/headNeg, and the
+// ready-made Base62 and Base64 Codecs do exactly that: only a key's first
+// byte is ever interpreted against headPos/headNeg, every later byte
+// against digits, so there's no decoding ambiguity from the two alphabets
+// overlapping.
+func NewCodec(digits, headPos, headNeg string) (*Codec, error) {
+	if len(digits) < 2 {
+		return nil, fmt.Errorf("digits must have at least 2 characters: %s", digits)
+	}
+	if !isStrictlyAscending(digits) {
+		return nil, fmt.Errorf("digits must be strictly ASCII-sorted: %s", digits)
+	}
+	if len(headPos) == 0 || !isStrictlyAscending(headPos) {
+		return nil, fmt.Errorf("headPos must be non-empty and strictly ASCII-sorted: %s", headPos)
+	}
+	if len(headNeg) == 0 || !isStrictlyAscending(headNeg) {
+		return nil, fmt.Errorf("headNeg must be non-empty and strictly ASCII-sorted: %s", headNeg)
+	}
+	if rangesOverlap(headPos, headNeg) {
+		return nil, fmt.Errorf("headPos and headNeg must not overlap: %s, %s", headPos, headNeg)
+	}
+
+	zero := headPos[0:1] + digits[0:1]
+	smallestInt := headNeg[0:1] + strings.Repeat(digits[0:1], len(headNeg))
+
+	return &Codec{
+		digits:      digits,
+		headPos:     headPos,
+		headNeg:     headNeg,
+		smallestInt: smallestInt,
+		zero:        zero,
+	}, nil
+}
+
+func mustNewCodec(digits, headPos, headNeg string) *Codec {
+	c, err := NewCodec(digits, headPos, headNeg)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func isStrictlyAscending(s string) bool {
+	for i := 1; i < len(s); i++ {
+		if s[i] <= s[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+func rangesOverlap(a, b string) bool {
+	for i := 0; i < len(a); i++ {
+		if strings.IndexByte(b, a[i]) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Base62 is the default Codec, matching the behavior of the package-level
+// KeyBetween and NKeysBetween functions.
+var Base62 = mustNewCodec(base62Digits, headPosDigits, headNegDigits)
+
+// Base64 is a Codec over a URL-safe base64 alphabet. Its larger digit set
+// produces shorter keys and lets a single midpoint step insert more items
+// before the fractional part needs to grow.
+var Base64 = mustNewCodec(base64Digits, headPosDigits, headNegDigits)
+
+var defaultCodec = Base62
 
 // KeyBetween returns a key that sorts lexicographically between a and b.
 // Either a or b can be empty strings. If a is empty it indicates smallest key,
 // If b is empty it indicates largest key.
 // b must be empty string or > a.
+//
+// KeyBetween uses the default base62 alphabet; see Codec.KeyBetween to use
+// a different one.
 func KeyBetween(a, b string) (string, error) {
+	return defaultCodec.KeyBetween(a, b)
+}
+
+// NKeysBetween returns n keys that sort lexicographically between a and b.
+// The result is the same as calling KeyBetween n times and using each
+// result as the new upper (or lower) bound, but does so more efficiently.
+//
+// NKeysBetween uses the default base62 alphabet; see Codec.NKeysBetween to
+// use a different one.
+func NKeysBetween(a, b string, n uint) ([]string, error) {
+	return defaultCodec.NKeysBetween(a, b, n)
+}
+
+// KeyBetween returns a key that sorts lexicographically between a and b.
+// Either a or b can be empty strings. If a is empty it indicates smallest key,
+// If b is empty it indicates largest key.
+// b must be empty string or > a.
+func (c *Codec) KeyBetween(a, b string) (string, error) {
 	if a != "" {
-		err := validateOrderKey(a)
+		err := c.validateOrderKey(a)
 		if err != nil {
 			return "", err
 		}
 	}
 	if b != "" {
-		err := validateOrderKey(b)
+		err := c.validateOrderKey(b)
 		if err != nil {
 			return "", err
 		}
@@ -33,60 +159,69 @@ func KeyBetween(a, b string) (string, error) {
 	}
 	if a == "" {
 		if b == "" {
-			return zero, nil
+			return c.zero, nil
 		}
 
-		ib, err := getIntPart(b)
+		ib, err := c.getIntPart(b)
 		if err != nil {
 			return "", err
 		}
 		fb := b[len(ib):]
-		if ib == smallestInt {
-			return ib + midpoint("", fb), nil
+		if ib == c.smallestInt {
+			return ib + c.midpoint("", fb), nil
 		}
 		if ib < b {
 			return ib, nil
 		}
-		res, err := decrementInt(ib)
+		res, err := c.decrementInt(ib)
 		if err != nil {
 			return "", err
 		}
 		if res == "" {
 			return "", errors.New("range underflow")
 		}
+		if res == c.smallestInt {
+			// decrementInt can land on the reserved smallest-integer
+			// sentinel as an ordinary result (not just when ib already was
+			// it, handled above) once headNeg is short enough that its
+			// widest bracket is reachable by an ordinary decrement. Treat
+			// it the same way: grow a fraction below it instead of
+			// returning the unusable sentinel itself.
+			return res + c.midpoint("", fb), nil
+		}
 		return res, nil
 	}
 
 	if b == "" {
-		ia, err := getIntPart(a)
+		ia, err := c.getIntPart(a)
 		if err != nil {
 			return "", err
 		}
 		fa := a[len(ia):]
-		i, err := incrementInt(ia)
+		i, err := c.incrementInt(ia)
 		if err != nil {
 			return "", err
 		}
 		if i == "" {
-			return ia + midpoint(fa, ""), nil
+			return ia + c.midpoint(fa, ""), nil
 		}
 		return i, nil
 	}
 
-	ia, err := getIntPart(a)
+	ia, err := c.getIntPart(a)
 	if err != nil {
 		return "", err
 	}
 	fa := a[len(ia):]
-	ib, err := getIntPart(b)
+	ib, err := c.getIntPart(b)
 	if err != nil {
 		return "", err
 	}
 	fb := b[len(ib):]
 	if ia == ib {
-		return ia + midpoint(fa, fb), nil
+		return ia + c.midpoint(fa, fb), nil
 	}
-	i, err := incrementInt(ia)
+	i, err := c.incrementInt(ia)
 	if err != nil {
 		return "", err
 	}
@@ -96,44 +231,120 @@ func KeyBetween(a, b string) (string, error) {
 	if i < b {
 		return i, nil
 	}
-	return ia + midpoint(fa, ""), nil
+	return ia + c.midpoint(fa, ""), nil
+}
+
+// NKeysBetween returns n keys that sort lexicographically between a and b.
+// The result is the same as calling KeyBetween n times and using each
+// result as the new upper (or lower) bound, but does so more efficiently.
+func (c *Codec) NKeysBetween(a, b string, n uint) ([]string, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if n == 1 {
+		k, err := c.KeyBetween(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return []string{k}, nil
+	}
+	if b == "" {
+		k, err := c.KeyBetween(a, "")
+		if err != nil {
+			return nil, err
+		}
+		result := make([]string, n)
+		result[0] = k
+		for i := uint(1); i < n; i++ {
+			k, err = c.KeyBetween(k, "")
+			if err != nil {
+				return nil, err
+			}
+			result[i] = k
+		}
+		return result, nil
+	}
+	if a == "" {
+		k, err := c.KeyBetween("", b)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]string, n)
+		result[n-1] = k
+		for i := int(n) - 2; i >= 0; i-- {
+			k, err = c.KeyBetween("", k)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = k
+		}
+		return result, nil
+	}
+
+	mid := n / 2
+	k, err := c.KeyBetween(a, b)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, n)
+	result[mid] = k
+
+	lower, err := c.NKeysBetween(a, k, mid)
+	if err != nil {
+		return nil, err
+	}
+	copy(result, lower)
+
+	upper, err := c.NKeysBetween(k, b, n-mid-1)
+	if err != nil {
+		return nil, err
+	}
+	copy(result[mid+1:], upper)
+
+	return result, nil
 }
 
 // `a < b` lexicographically if `b` is non-empty.
 // a == "" means first possible string.
 // b == "" means last possible string.
-func midpoint(a string, b string) string {
+func (c *Codec) midpoint(a string, b string) string {
 	if b != "" {
-		// remove longest common prefix.  pad `a` with 0s as we
-		// go.  note that we don't need to pad `b`, because it can't
-		// end before `a` while traversing the common prefix.
+		// remove longest common prefix.  pad `a` with the zero digit as we
+		// go, past its own length if needed, since `a` may run out before
+		// the common prefix does (e.g. a="1", b="100005" share the prefix
+		// "10000"). we don't need to pad `b`, because it can't end before `a`
+		// while traversing the common prefix.
 		i := 0
-		for ; i < len(a); i++ {
-			c := byte(0)
-			if len(a) > i {
-				c = a[i]
+		for ; i < len(b); i++ {
+			d := c.digits[0]
+			if i < len(a) {
+				d = a[i]
 			}
-			if i >= len(b) || c != b[i] {
+			if d != b[i] {
 				break
 			}
 		}
 		if i > 0 {
-			return b[0:i] + midpoint(a[i:], b[i:])
+			aRest := ""
+			if i < len(a) {
+				aRest = a[i:]
+			}
+			return b[0:i] + c.midpoint(aRest, b[i:])
 		}
 	}
 
 	// first digits (or lack of digit) are different
 	digitA := 0
 	if a != "" {
-		digitA = strings.Index(base62Digits, string(a[0]))
+		digitA = strings.IndexByte(c.digits, a[0])
 	}
-	digitB := len(base62Digits)
+	digitB := len(c.digits)
 	if b != "" {
-		digitB = strings.Index(base62Digits, string(b[0]))
+		digitB = strings.IndexByte(c.digits, b[0])
 	}
 	if digitB-digitA > 1 {
 		midDigit := int(math.Round(0.5 * float64(digitA+digitB)))
-		return string(base62Digits[midDigit])
+		return string(c.digits[midDigit])
 	}
 
 	// first digits are consecutive
@@ -143,7 +354,7 @@ func midpoint(a string, b string) string {
 
 	// `b` is empty or has length 1 (a single digit).
 	// the first digit of `a` is the previous digit to `b`,
-	// or 9 if `b` is null.
+	// or the last digit if `b` is null.
 	// given, for example, midpoint('49', '5'), return
 	// '4' + midpoint('9', null), which will become
 	// '4' + '9' + midpoint('', null), which is '495'
@@ -151,32 +362,35 @@ func midpoint(a string, b string) string {
 	if len(a) > 0 {
 		sa = a[1:]
 	}
-	return string(base62Digits[digitA]) + midpoint(sa, "")
+	return string(c.digits[digitA]) + c.midpoint(sa, "")
 }
 
-func validateInt(i string) error {
-	exp, err := getIntLen(i[0])
+func (c *Codec) validateInt(i string) error {
+	exp, err := c.getIntLen(i[0])
 	if err != nil {
 		return err
 	}
 	if len(i) != exp {
-		return fmt.Errorf("invalid integer part of order key: %s" + i)
+		return fmt.Errorf("invalid integer part of order key: %s", i)
 	}
 	return nil
 }
 
-func getIntLen(head byte) (int, error) {
-	if head >= 'a' && head <= 'z' {
-		return int(head - 'a' + 2), nil
-	} else if head >= 'A' && head <= 'Z' {
-		return int('Z' - head + 2), nil
-	} else {
-		return 0, fmt.Errorf("invalid order key head: %s", string(head))
+// getIntLen returns the length of the integer part of a key whose first
+// character (the "head") is head, or an error if head isn't a valid head
+// character for this Codec.
+func (c *Codec) getIntLen(head byte) (int, error) {
+	if i := strings.IndexByte(c.headPos, head); i >= 0 {
+		return i + 2, nil
+	}
+	if i := strings.IndexByte(c.headNeg, head); i >= 0 {
+		return len(c.headNeg) - i + 1, nil
 	}
+	return 0, fmt.Errorf("invalid order key head: %s", string(head))
 }
 
-func getIntPart(key string) (string, error) {
-	intPartLen, err := getIntLen(key[0])
+func (c *Codec) getIntPart(key string) (string, error) {
+	intPartLen, err := c.getIntLen(key[0])
 	if err != nil {
 		return "", err
 	}
@@ -186,27 +400,27 @@ func getIntPart(key string) (string, error) {
 	return key[0:intPartLen], nil
 }
 
-func validateOrderKey(key string) error {
-	if key == smallestInt {
+func (c *Codec) validateOrderKey(key string) error {
+	if key == c.smallestInt {
 		return fmt.Errorf("invalid order key: %s", key)
 	}
 	// getIntPart will return error if the first character is bad,
 	// or the key is too short.  we'd call it to check these things
 	// even if we didn't need the result
-	i, err := getIntPart(key)
+	i, err := c.getIntPart(key)
 	if err != nil {
 		return err
 	}
 	f := key[len(i):]
-	if strings.HasSuffix(f, "0") {
+	if strings.HasSuffix(f, c.digits[0:1]) {
 		return fmt.Errorf("invalid order key: %s", key)
 	}
 	return nil
 }
 
-// returns error if x is invalid, or if range is exceeded
-func incrementInt(x string) (string, error) {
-	err := validateInt(x)
+// returns error if x is invalid, or "" if the range is exceeded
+func (c *Codec) incrementInt(x string) (string, error) {
+	err := c.validateInt(x)
 	if err != nil {
 		return "", err
 	}
@@ -215,34 +429,39 @@ func incrementInt(x string) (string, error) {
 	digs = digs[1:]
 	carry := true
 	for i := len(digs) - 1; carry && i >= 0; i-- {
-		d := strings.Index(base62Digits, digs[i]) + 1
-		if d == len(base62Digits) {
-			digs[i] = "0"
+		d := strings.IndexByte(c.digits, digs[i][0]) + 1
+		if d == len(c.digits) {
+			digs[i] = c.digits[0:1]
 		} else {
-			digs[i] = string(base62Digits[d])
+			digs[i] = string(c.digits[d])
 			carry = false
 		}
 	}
-	if carry {
-		if head == "Z" {
-			return "a0", nil
-		}
-		if head == "z" {
+	if !carry {
+		return head + strings.Join(digs, ""), nil
+	}
+
+	if ip := strings.IndexByte(c.headPos, head[0]); ip >= 0 {
+		if ip == len(c.headPos)-1 {
+			// already the longest possible positive integer part
 			return "", nil
 		}
-		h := string(head[0] + 1)
-		if h > "a" {
-			digs = append(digs, "0")
-		} else {
-			digs = digs[1:]
-		}
-		return string(h) + strings.Join(digs, ""), nil
+		digs = append(digs, c.digits[0:1])
+		return string(c.headPos[ip+1]) + strings.Join(digs, ""), nil
 	}
-	return head + strings.Join(digs, ""), nil
+
+	in := strings.IndexByte(c.headNeg, head[0])
+	if in == len(c.headNeg)-1 {
+		// cross over into the smallest positive integer part
+		return c.zero, nil
+	}
+	digs = digs[1:]
+	return string(c.headNeg[in+1]) + strings.Join(digs, ""), nil
 }
 
-func decrementInt(x string) (string, error) {
-	err := validateInt(x)
+// returns error if x is invalid, or "" if the range is exceeded
+func (c *Codec) decrementInt(x string) (string, error) {
+	err := c.validateInt(x)
 	if err != nil {
 		return "", err
 	}
@@ -251,30 +470,32 @@ func decrementInt(x string) (string, error) {
 	digs = digs[1:]
 	borrow := true
 	for i := len(digs) - 1; borrow && i >= 0; i-- {
-		d := strings.Index(base62Digits, digs[i]) - 1
+		d := strings.IndexByte(c.digits, digs[i][0]) - 1
 		if d == -1 {
-			digs[i] = string(base62Digits[len(base62Digits)-1])
+			digs[i] = c.digits[len(c.digits)-1:]
 		} else {
-			digs[i] = string(base62Digits[d])
+			digs[i] = string(c.digits[d])
 			borrow = false
 		}
 	}
+	if !borrow {
+		return head + strings.Join(digs, ""), nil
+	}
 
-	if borrow {
-		if head == "a" {
-			return "Z" + string(base62Digits[len(base62Digits)-1]), nil
-		}
-		if head == "A" {
+	if in := strings.IndexByte(c.headNeg, head[0]); in >= 0 {
+		if in == 0 {
+			// already the most negative (longest) integer part
 			return "", nil
 		}
-		h := head[0] - 1
-		if h < 'Z' {
-			digs = append(digs, string(base62Digits[len(base62Digits)-1]))
-		} else {
-			digs = digs[1:]
-		}
-		return string(h) + strings.Join(digs, ""), nil
+		digs = append(digs, c.digits[len(c.digits)-1:])
+		return string(c.headNeg[in-1]) + strings.Join(digs, ""), nil
 	}
 
-	return head + strings.Join(digs, ""), nil
-}
\ No newline at end of file
+	ip := strings.IndexByte(c.headPos, head[0])
+	if ip == 0 {
+		// cross over into the shortest negative integer part
+		return string(c.headNeg[len(c.headNeg)-1]) + c.digits[len(c.digits)-1:], nil
+	}
+	digs = digs[1:]
+	return string(c.headPos[ip-1]) + strings.Join(digs, ""), nil
+}
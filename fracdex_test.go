@@ -52,6 +52,9 @@ func TestKeys(t *testing.T) {
 	test("a00", "a1", "invalid order key: a00")
 	test("0", "1", "invalid order key head: 0")
 	test("a1", "a0", "a1 >= a0")
+	// a's fraction runs out before the common prefix does; midpoint must
+	// keep padding with the zero digit rather than stopping at a's length.
+	test("a11", "a1100005", "a1100003")
 }
 
 func TestNKeys(t *testing.T) {
@@ -78,3 +81,108 @@ func TestNKeys(t *testing.T) {
 		"a04 a08 a0G a0K a0O a0V a0Z a0d a0l a0t a1 a14 a18 a1G a1O a1V a1Z a1d a1l a1t",
 	)
 }
+
+// TestNKeysBetweenShortHeadNeg exercises a Codec whose headNeg is a single
+// character, so its widest negative integer-part bracket is exhausted by an
+// ordinary decrement long before n is large. decrementInt must cross into
+// the reserved smallestInt sentinel as an intermediate result here (not just
+// when it's handed directly as b's integer part), and KeyBetween must grow
+// a fraction below it rather than returning the sentinel itself.
+func TestNKeysBetweenShortHeadNeg(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := NewCodec("0123456789", "a", "A")
+	assert.Nil(err)
+
+	keys, err := c.NKeysBetween("", "a0", 50)
+	assert.Nil(err)
+	assert.Len(keys, 50)
+	for i, k := range keys {
+		assert.Nil(c.validateOrderKey(k), "key %d: %s", i, k)
+		if i > 0 {
+			assert.True(keys[i-1] < k, "keys not sorted at %d: %s >= %s", i, keys[i-1], k)
+		}
+	}
+}
+
+func TestBase64Keys(t *testing.T) {
+	assert := assert.New(t)
+
+	test := func(a, b, exp string) {
+		act, err := Base64.KeyBetween(a, b)
+		if err != nil {
+			assert.Equal("", act)
+			assert.Equal(exp, err.Error())
+		} else {
+			assert.Nil(err)
+			assert.Equal(exp, act)
+		}
+	}
+
+	test("", "", "a-")
+	test("", "a-", "Zz")
+	test("", "a_", "aZ")
+	test("a-", "", "a0")
+	test("a_", "", "aa")
+	test("a-", "a_", "a0")
+	test("Zz", "a-", "ZzV")
+	test(
+		"",
+		"A--------------------------",
+		"invalid order key: A--------------------------",
+	)
+	test("", "A-------------------------0", "A--------------------------V")
+	test("zzzzzzzzzzzzzzzzzzzzzzzzzzy", "", "zzzzzzzzzzzzzzzzzzzzzzzzzzz")
+	test("zzzzzzzzzzzzzzzzzzzzzzzzzzz", "", "zzzzzzzzzzzzzzzzzzzzzzzzzzzV")
+}
+
+func TestBase64NKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	test := func(a, b string, n uint, exp string) {
+		actSlice, err := Base64.NKeysBetween(a, b, n)
+		act := strings.Join(actSlice, " ")
+		if err != nil {
+			assert.Equal("", act)
+			assert.Equal(exp, err.Error())
+		} else {
+			assert.Nil(err)
+			assert.Equal(exp, act)
+		}
+	}
+	test("", "", 5, "a- a0 a1 a2 a3")
+	test("a_", "", 6, "aa ab ac ad ae af")
+	test("", "a-", 5, "Zv Zw Zx Zy Zz")
+}
+
+func TestNewCodecValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		name, digits, headPos, headNeg string
+	}{
+		{"digits too short", "0", "a", "A"},
+		{"digits not ascending", "10", "a", "A"},
+		{"digits has a repeat", "001", "a", "A"},
+		{"headPos empty", "012", "", "A"},
+		{"headPos not ascending", "012", "ba", "A"},
+		{"headNeg empty", "012", "a", ""},
+		{"headNeg not ascending", "012", "a", "BA"},
+		{"headPos and headNeg overlap", "012", "ab", "bc"},
+	}
+	for _, c := range cases {
+		_, err := NewCodec(c.digits, c.headPos, c.headNeg)
+		assert.NotNil(err, c.name)
+	}
+
+	codec, err := NewCodec("0123456789", "a", "A")
+	assert.Nil(err)
+	assert.NotNil(codec)
+
+	// digits may overlap headPos/headNeg: only a key's first byte is ever
+	// decoded against the head ranges, so there's no ambiguity. Base62 and
+	// Base64 both rely on this.
+	overlapping, err := NewCodec("ab", "a", "A")
+	assert.Nil(err)
+	assert.NotNil(overlapping)
+}
@@ -0,0 +1,144 @@
+package fracdex
+
+import (
+	"io"
+	"math"
+	"strings"
+)
+
+// defaultJitterBits is used when JitterOptions is nil or its JitterBits
+// field is zero. ~30 bits (~5 base62 digits) keeps the birthday-bound
+// collision probability negligible for the write concurrency most callers
+// have (see KeyBetweenJittered's doc comment).
+const defaultJitterBits = 30
+
+// JitterOptions configures KeyBetweenJittered and NKeysBetweenJittered.
+type JitterOptions struct {
+	// JitterBits is the number of bits of randomness appended to each key.
+	// Larger values make independently-generated keys less likely to
+	// collide, at the cost of longer keys. Zero means defaultJitterBits.
+	JitterBits int
+}
+
+func (o *JitterOptions) jitterBits() int {
+	if o == nil || o.JitterBits <= 0 {
+		return defaultJitterBits
+	}
+	return o.JitterBits
+}
+
+// KeyBetweenJittered is like KeyBetween, except it appends a random suffix
+// to the deterministic midpoint so that two clients who independently call
+// KeyBetweenJittered(a, b, ...) with the same neighbours don't produce the
+// same key. This lets offline or CRDT-style clients assign order keys
+// without coordinating with each other, at the cost of a small, bounded
+// chance of collision if two of them pick the same key anyway.
+//
+// With JitterBits bits of randomness (opts.JitterBits, default 30), the
+// chance that k independently-generated keys collide is bounded by the
+// usual birthday approximation, k*(k-1)/2^(JitterBits+1); size JitterBits
+// so that bound is acceptable for your expected write concurrency.
+//
+// If KeyBetween(a, b) is a prefix of b, the suffix is appended to a key
+// between it and b that is not a prefix of b, so the full suffix still fits.
+//
+// rng is read once per call for the jitter; a *rand.Rand or crypto/rand.Reader
+// both work.
+func (c *Codec) KeyBetweenJittered(a, b string, rng io.Reader, opts *JitterOptions) (string, error) {
+	m, err := c.KeyBetween(a, b)
+	if err != nil {
+		return "", err
+	}
+	for b != "" && strings.HasPrefix(b, m) {
+		m, err = c.KeyBetween(m, b)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	bits := opts.jitterBits()
+	digits := int(math.Ceil(float64(bits) / math.Log2(float64(len(c.digits)))))
+	suffix, err := c.randomDigits(rng, digits)
+	if err != nil {
+		return "", err
+	}
+
+	return appendJitter(c, m, b, suffix), nil
+}
+
+// NKeysBetweenJittered is the jittered counterpart of NKeysBetween: it
+// returns n keys that sort between a and b, each with a random suffix so
+// that concurrent, uncoordinated calls are unlikely to produce the same
+// sequence of keys.
+func (c *Codec) NKeysBetweenJittered(a, b string, n uint, rng io.Reader, opts *JitterOptions) ([]string, error) {
+	base, err := c.NKeysBetween(a, b, n)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, n)
+	prev := a
+	for i := uint(0); i < n; i++ {
+		upper := b
+		if i+1 < n {
+			upper = base[i+1]
+		}
+		k, err := c.KeyBetweenJittered(prev, upper, rng, opts)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = k
+		prev = k
+	}
+	return result, nil
+}
+
+// KeyBetweenJittered is the package-level counterpart of
+// Codec.KeyBetweenJittered, using the default base62 alphabet.
+func KeyBetweenJittered(a, b string, rng io.Reader, opts *JitterOptions) (string, error) {
+	return defaultCodec.KeyBetweenJittered(a, b, rng, opts)
+}
+
+// NKeysBetweenJittered is the package-level counterpart of
+// Codec.NKeysBetweenJittered, using the default base62 alphabet.
+func NKeysBetweenJittered(a, b string, n uint, rng io.Reader, opts *JitterOptions) ([]string, error) {
+	return defaultCodec.NKeysBetweenJittered(a, b, n, rng, opts)
+}
+
+func (c *Codec) randomDigits(rng io.Reader, n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(rng, buf); err != nil {
+		return "", err
+	}
+	base := len(c.digits)
+	out := make([]byte, n)
+	for i, v := range buf {
+		out[i] = c.digits[int(v)%base]
+	}
+	return string(out), nil
+}
+
+// appendJitter appends as much of suffix to m as will still (a) not end in
+// the zero digit, so the result satisfies validateOrderKey, and (b) sort
+// strictly before b. KeyBetweenJittered only passes an m that is not a
+// prefix of b, so every suffix keeps the result before b there; for an m
+// that is a prefix of b, this falls back to a shorter suffix, down to no
+// jitter at all rather than ever producing an invalid key.
+func appendJitter(c *Codec, m, b, suffix string) string {
+	trim := func(s string) string {
+		for len(s) > 0 && s[len(s)-1] == c.digits[0] {
+			s = s[:len(s)-1]
+		}
+		return s
+	}
+
+	suffix = trim(suffix)
+	for len(suffix) > 0 {
+		candidate := m + suffix
+		if b == "" || candidate < b {
+			return candidate
+		}
+		suffix = trim(suffix[:len(suffix)-1])
+	}
+	return m
+}
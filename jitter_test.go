@@ -0,0 +1,64 @@
+package fracdex
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyBetweenJittered(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	a := ""
+	for i := 0; i < 200; i++ {
+		k, err := KeyBetweenJittered(a, "", r, nil)
+		assert.Nil(err)
+		if a != "" {
+			assert.True(k > a)
+		}
+		a = k
+	}
+}
+
+func TestKeyBetweenJitteredDoesNotOverrunAPrefixBound(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewSource(2))
+
+	// KeyBetween("Zz", "a01") == "a0", which is an exact prefix of b; the
+	// jittered suffix must still land strictly inside (a, b).
+	for i := 0; i < 200; i++ {
+		k, err := KeyBetweenJittered("Zz", "a01", r, nil)
+		assert.Nil(err)
+		assert.True(k > "Zz")
+		assert.True(k < "a01")
+	}
+}
+
+func TestKeyBetweenJitteredRespectsJitterBits(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewSource(3))
+
+	k, err := KeyBetweenJittered("a0", "a1", r, &JitterOptions{JitterBits: 60})
+	assert.Nil(err)
+	assert.True(k > "a0")
+	assert.True(k < "a1")
+	// 60 bits take ceil(60/log2(62)) = 11 base62 digits, appended to
+	// KeyBetween("a0", "a1") == "a0V".
+	assert.Equal(len("a0V")+11, len(k))
+}
+
+func TestNKeysBetweenJittered(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewSource(4))
+
+	ks, err := NKeysBetweenJittered("a0", "a1", 30, r, nil)
+	assert.Nil(err)
+	assert.Equal(30, len(ks))
+	assert.True(ks[0] > "a0")
+	assert.True(ks[len(ks)-1] < "a1")
+	for i := 1; i < len(ks); i++ {
+		assert.True(ks[i-1] < ks[i])
+	}
+}
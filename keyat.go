@@ -0,0 +1,397 @@
+package fracdex
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// KeyAt returns the same key as NKeysBetween(a, b, n)[i], but computes it
+// directly from a and b instead of generating and discarding the other n-1
+// keys. This makes it practical to hand out disjoint slices of a single
+// (a, b) range to paginated inserts, virtualised lists, or parallel workers
+// that each need one key from a known index without coordinating on the
+// rest.
+//
+// The fast path mirrors NKeysBetween's own algorithm rather than
+// approximating it: when a and b are both given, NKeysBetween picks
+// KeyBetween(a, b) as the middle key and recurses on each half, so KeyAt
+// follows that same single root-to-leaf path (O(log n) KeyBetween calls)
+// instead of also computing the n/2 keys on the other side of each split.
+// When a or b is empty (the common case for appending to the end of a list,
+// or populating one from scratch), NKeysBetween instead walks a chain of
+// increments or decrements, so KeyAt jumps straight to the i-th one with
+// O(len(key)) big.Int arithmetic over the integer part.
+func (c *Codec) KeyAt(a, b string, i, n uint) (string, error) {
+	if i >= n {
+		return "", fmt.Errorf("index %d out of range for n=%d", i, n)
+	}
+	if a != "" {
+		if err := c.validateOrderKey(a); err != nil {
+			return "", err
+		}
+	}
+	if b != "" {
+		if err := c.validateOrderKey(b); err != nil {
+			return "", err
+		}
+	}
+	if a != "" && b != "" && a >= b {
+		return "", fmt.Errorf("%s >= %s", a, b)
+	}
+
+	if a != "" && b != "" {
+		return c.keyAtBisect(a, b, i, n)
+	} else if b == "" {
+		if key, ok := c.keyAtOpenRight(a, i, n); ok {
+			return key, nil
+		}
+	} else if a == "" {
+		if key, ok := c.keyAtOpenLeft(b, i, n); ok {
+			return key, nil
+		}
+	}
+
+	keys, err := c.NKeysBetween(a, b, n)
+	if err != nil {
+		return "", err
+	}
+	return keys[i], nil
+}
+
+// keyAtBisect mirrors NKeysBetween's recursive case: NKeysBetween(a, b, n)
+// splits n into a lower half of size mid, the pivot KeyBetween(a, b), and an
+// upper half of size n-mid-1, then recurses. keyAtBisect follows the same
+// split but only recurses into the half containing i, so it does O(log n)
+// KeyBetween calls instead of materializing both halves.
+func (c *Codec) keyAtBisect(a, b string, i, n uint) (string, error) {
+	if n == 1 {
+		return c.KeyBetween(a, b)
+	}
+	mid := n / 2
+	k, err := c.KeyBetween(a, b)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case i == mid:
+		return k, nil
+	case i < mid:
+		return c.keyAtBisect(a, k, i, mid)
+	default:
+		return c.keyAtBisect(k, b, i-mid-1, n-mid-1)
+	}
+}
+
+// IndexOf returns the index i such that KeyAt(a, b, i, n) == key, i.e. it
+// inverts KeyAt. key must have been produced by KeyAt (or equivalently
+// NKeysBetween) called with the same a, b and n.
+func (c *Codec) IndexOf(a, b, key string, n uint) (uint, error) {
+	if err := c.validateOrderKey(key); err != nil {
+		return 0, err
+	}
+	if a != "" {
+		if err := c.validateOrderKey(a); err != nil {
+			return 0, err
+		}
+	}
+	if b != "" {
+		if err := c.validateOrderKey(b); err != nil {
+			return 0, err
+		}
+	}
+
+	if a != "" && b != "" {
+		if idx, err, ok := c.indexOfBisect(a, b, key, n); ok {
+			return idx, err
+		}
+	} else if b == "" {
+		if idx, ok := c.indexOfOpenRight(a, key, n); ok {
+			return idx, nil
+		}
+	} else if a == "" {
+		if idx, ok := c.indexOfOpenLeft(b, key, n); ok {
+			return idx, nil
+		}
+	}
+
+	keys, err := c.NKeysBetween(a, b, n)
+	if err != nil {
+		return 0, err
+	}
+	for idx, k := range keys {
+		if k == key {
+			return uint(idx), nil
+		}
+	}
+	return 0, fmt.Errorf("key %s is not one of the n=%d keys between %s and %s", key, n, a, b)
+}
+
+// indexOfBisect inverts keyAtBisect, following the same single root-to-leaf
+// path: at each split it recomputes the pivot KeyBetween(a, b) and uses
+// key's position relative to it (key's sort position among the n keys is
+// the same as among the generated ones) to pick which half to recurse into.
+// ok is false if a or b turned out not to be valid order keys, in which
+// case the caller should fall back to NKeysBetween; once ok is true, err
+// reports whether key genuinely isn't one of the n keys.
+func (c *Codec) indexOfBisect(a, b, key string, n uint) (uint, error, bool) {
+	if n == 1 {
+		k, err := c.KeyBetween(a, b)
+		if err != nil {
+			return 0, nil, false
+		}
+		if key != k {
+			return 0, fmt.Errorf("key %s is not one of the n=1 keys between %s and %s", key, a, b), true
+		}
+		return 0, nil, true
+	}
+	mid := n / 2
+	k, err := c.KeyBetween(a, b)
+	if err != nil {
+		return 0, nil, false
+	}
+	switch {
+	case key == k:
+		return mid, nil, true
+	case key < k:
+		return c.indexOfBisect(a, k, key, mid)
+	default:
+		idx, err, ok := c.indexOfBisect(k, b, key, n-mid-1)
+		return mid + 1 + idx, err, ok
+	}
+}
+
+// KeyAt is the package-level counterpart of Codec.KeyAt, using the default
+// base62 alphabet.
+func KeyAt(a, b string, i, n uint) (string, error) {
+	return defaultCodec.KeyAt(a, b, i, n)
+}
+
+// IndexOf is the package-level counterpart of Codec.IndexOf, using the
+// default base62 alphabet.
+func IndexOf(a, b, key string, n uint) (uint, error) {
+	return defaultCodec.IndexOf(a, b, key, n)
+}
+
+// keyAtOpenRight computes NKeysBetween(a, "", n)[i] directly. When b is
+// empty, that sequence is always n consecutive integer parts starting at
+// KeyBetween(a, ""): NKeysBetween builds it by repeatedly calling
+// KeyBetween(prev, ""), which - as long as it doesn't have to fall back to a
+// fractional midpoint because the integer range is exhausted - just
+// increments the integer part by one each time. keyAtOpenRight recognizes
+// that case and jumps straight to the i-th integer part with one bit of
+// big.Int arithmetic instead of i increments. ok is false if that
+// assumption doesn't hold (e.g. the integer range really is exhausted), in
+// which case the caller should fall back to NKeysBetween.
+func (c *Codec) keyAtOpenRight(a string, i, n uint) (string, bool) {
+	k0, err := c.KeyBetween(a, "")
+	if err != nil {
+		return "", false
+	}
+	ip0, err := c.getIntPart(k0)
+	if err != nil || k0 != ip0 {
+		return "", false
+	}
+	v0, err := c.intPartValue(ip0)
+	if err != nil {
+		return "", false
+	}
+	target := new(big.Int).Add(v0, big.NewInt(int64(i)))
+	key, err := c.intPartFromValue(target)
+	if err != nil || key == c.smallestInt {
+		return "", false
+	}
+	return key, true
+}
+
+// keyAtOpenLeft is keyAtOpenRight's mirror image for NKeysBetween("", b, n):
+// that sequence is n consecutive integer parts ending at KeyBetween("", b),
+// built by repeatedly decrementing.
+func (c *Codec) keyAtOpenLeft(b string, i, n uint) (string, bool) {
+	k0, err := c.KeyBetween("", b)
+	if err != nil {
+		return "", false
+	}
+	ip0, err := c.getIntPart(k0)
+	if err != nil || k0 != ip0 {
+		return "", false
+	}
+	v0, err := c.intPartValue(ip0)
+	if err != nil {
+		return "", false
+	}
+	target := new(big.Int).Sub(v0, big.NewInt(int64(n-1-i)))
+	key, err := c.intPartFromValue(target)
+	if err != nil || key == c.smallestInt {
+		return "", false
+	}
+	return key, true
+}
+
+// indexOfOpenRight inverts keyAtOpenRight.
+func (c *Codec) indexOfOpenRight(a, key string, n uint) (uint, bool) {
+	keyIp, err := c.getIntPart(key)
+	if err != nil || key != keyIp {
+		return 0, false
+	}
+	k0, err := c.KeyBetween(a, "")
+	if err != nil {
+		return 0, false
+	}
+	ip0, err := c.getIntPart(k0)
+	if err != nil || k0 != ip0 {
+		return 0, false
+	}
+	v0, err := c.intPartValue(ip0)
+	if err != nil {
+		return 0, false
+	}
+	vk, err := c.intPartValue(keyIp)
+	if err != nil {
+		return 0, false
+	}
+	idx := new(big.Int).Sub(vk, v0)
+	if !idx.IsInt64() {
+		return 0, false
+	}
+	idx64 := idx.Int64()
+	if idx64 < 0 || idx64 >= int64(n) {
+		return 0, false
+	}
+	return uint(idx64), true
+}
+
+// indexOfOpenLeft inverts keyAtOpenLeft.
+func (c *Codec) indexOfOpenLeft(b, key string, n uint) (uint, bool) {
+	keyIp, err := c.getIntPart(key)
+	if err != nil || key != keyIp {
+		return 0, false
+	}
+	k0, err := c.KeyBetween("", b)
+	if err != nil {
+		return 0, false
+	}
+	ip0, err := c.getIntPart(k0)
+	if err != nil || k0 != ip0 {
+		return 0, false
+	}
+	v0, err := c.intPartValue(ip0)
+	if err != nil {
+		return 0, false
+	}
+	vk, err := c.intPartValue(keyIp)
+	if err != nil {
+		return 0, false
+	}
+	idx := new(big.Int).Sub(vk, v0)
+	idx.Add(idx, big.NewInt(int64(n-1)))
+	if !idx.IsInt64() {
+		return 0, false
+	}
+	idx64 := idx.Int64()
+	if idx64 < 0 || idx64 >= int64(n) {
+		return 0, false
+	}
+	return uint(idx64), true
+}
+
+// valueOf interprets s as a base-len(c.digits) positional number using each
+// character's index in c.digits as its digit value.
+func (c *Codec) valueOf(s string) (*big.Int, error) {
+	base := big.NewInt(int64(len(c.digits)))
+	v := new(big.Int)
+	for i := 0; i < len(s); i++ {
+		idx := strings.IndexByte(c.digits, s[i])
+		if idx < 0 {
+			return nil, fmt.Errorf("character %q is not in this codec's digit alphabet", s[i])
+		}
+		v.Mul(v, base)
+		v.Add(v, big.NewInt(int64(idx)))
+	}
+	return v, nil
+}
+
+// digitsOf is the inverse of valueOf: it renders v as exactly `length`
+// characters of c.digits, left-padded with the zero digit.
+func (c *Codec) digitsOf(v *big.Int, length int) string {
+	base := big.NewInt(int64(len(c.digits)))
+	digs := make([]byte, length)
+	rem := new(big.Int)
+	tmp := new(big.Int).Set(v)
+	for i := length - 1; i >= 0; i-- {
+		tmp.DivMod(tmp, base, rem)
+		digs[i] = c.digits[rem.Int64()]
+	}
+	return string(digs)
+}
+
+// bracketOffsetAndWidth returns, for the rank-th bracket out of a head range
+// (ranks counted outward from whichever end sits closest to the zero
+// integer part), the cumulative count of keys in all narrower brackets
+// (offset) and the number of keys in this bracket itself (width, i.e.
+// len(c.digits)^(rank+1)).
+func (c *Codec) bracketOffsetAndWidth(rank int) (offset, width *big.Int) {
+	base := big.NewInt(int64(len(c.digits)))
+	offset = new(big.Int)
+	width = new(big.Int).Set(base)
+	for k := 0; k < rank; k++ {
+		offset.Add(offset, width)
+		width.Mul(width, base)
+	}
+	return offset, width
+}
+
+// intPartValue and intPartFromValue are inverses of each other: they form a
+// bijection between valid integer parts and a contiguous range of signed
+// big.Ints, with c.zero at 0, positive integer parts at positive values in
+// the order headPos encodes, and negative ones at negative values in the
+// order headNeg encodes. This lets keyAtOpenRight/keyAtOpenLeft reach the
+// i-th successor (or predecessor) of a given integer part by adding (or
+// subtracting) i directly, rather than by incrementing/decrementing it i
+// times.
+func (c *Codec) intPartValue(ip string) (*big.Int, error) {
+	head := ip[0]
+	dv, err := c.valueOf(ip[1:])
+	if err != nil {
+		return nil, err
+	}
+	if idx := strings.IndexByte(c.headPos, head); idx >= 0 {
+		offset, _ := c.bracketOffsetAndWidth(idx)
+		return offset.Add(offset, dv), nil
+	}
+	if idx := strings.IndexByte(c.headNeg, head); idx >= 0 {
+		rank := len(c.headNeg) - 1 - idx
+		offset, width := c.bracketOffsetAndWidth(rank)
+		v := width.Sub(width, dv)
+		v.Add(v, offset)
+		return v.Neg(v), nil
+	}
+	return nil, fmt.Errorf("invalid order key head: %s", string(head))
+}
+
+func (c *Codec) intPartFromValue(v *big.Int) (string, error) {
+	if v.Sign() >= 0 {
+		for idx := 0; idx < len(c.headPos); idx++ {
+			offset, width := c.bracketOffsetAndWidth(idx)
+			upper := new(big.Int).Add(offset, width)
+			if v.Cmp(upper) < 0 {
+				dv := new(big.Int).Sub(v, offset)
+				return string(c.headPos[idx]) + c.digitsOf(dv, idx+1), nil
+			}
+		}
+		return "", fmt.Errorf("integer part out of range: overflow")
+	}
+
+	w := new(big.Int).Neg(v)
+	for rank := 0; rank < len(c.headNeg); rank++ {
+		offset, width := c.bracketOffsetAndWidth(rank)
+		upper := new(big.Int).Add(offset, width)
+		if w.Cmp(upper) <= 0 {
+			dv := new(big.Int).Sub(w, offset)
+			dv.Sub(width, dv)
+			idx := len(c.headNeg) - 1 - rank
+			return string(c.headNeg[idx]) + c.digitsOf(dv, rank+1), nil
+		}
+	}
+	return "", fmt.Errorf("integer part out of range: underflow")
+}
@@ -0,0 +1,137 @@
+package fracdex
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyAtMatchesNKeysBetween(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		a, b string
+		n    uint
+	}{
+		{"a0", "a1", 5},
+		{"a0", "a2", 20},
+		{"", "", 5},
+		{"a4", "", 10},
+		{"", "a0", 5},
+		{"a0", "b11", 7}, // a and b straddle an integer-part boundary
+		{"Zz", "a1", 9},
+		{"", "a5", 37},                           // a empty: keys are consecutive integer parts below b
+		{"a5", "", 37},                           // b empty: keys are consecutive integer parts above a
+		{"", "A100000000000000000000000005", 11}, // a empty, b deep in the negative brackets
+		{"A100000000000000000000000001", "", 11}, // a deep in the negative brackets, b empty
+	}
+	for _, c := range cases {
+		want, err := NKeysBetween(c.a, c.b, c.n)
+		assert.Nil(err)
+		for i := uint(0); i < c.n; i++ {
+			got, err := KeyAt(c.a, c.b, i, c.n)
+			assert.Nil(err)
+			assert.Equal(want[i], got)
+
+			idx, err := IndexOf(c.a, c.b, got, c.n)
+			assert.Nil(err)
+			assert.Equal(i, idx)
+		}
+	}
+}
+
+// TestKeyAtMatchesNKeysBetweenRandom sweeps randomly generated a/b/n over the
+// common same-integer-part case, where KeyAt must follow NKeysBetween's
+// recursive bisection rather than a uniform interpolation over the numeric
+// range (the two are not the same distribution).
+func TestKeyAtMatchesNKeysBetweenRandom(t *testing.T) {
+	assert := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+
+	randFrac := func() string {
+		length := rng.Intn(4)
+		if length == 0 {
+			return ""
+		}
+		digits := "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+		nonZero := digits[1:]
+		buf := make([]byte, length)
+		for i := range buf {
+			buf[i] = digits[rng.Intn(len(digits))]
+		}
+		buf[length-1] = nonZero[rng.Intn(len(nonZero))]
+		return string(buf)
+	}
+
+	for iter := 0; iter < 200; iter++ {
+		a := "a0" + randFrac()
+		b := "a0" + randFrac()
+		for a >= b {
+			b = "a0" + randFrac()
+		}
+		n := uint(rng.Intn(12) + 1)
+
+		// Pairs for which NKeysBetween errors, or returns a key that fails
+		// validateOrderKey, are skipped, so KeyAt/IndexOf are only compared
+		// against valid output.
+		want, err := NKeysBetween(a, b, n)
+		if err != nil {
+			continue
+		}
+		allValid := true
+		for _, k := range want {
+			if defaultCodec.validateOrderKey(k) != nil {
+				allValid = false
+				break
+			}
+		}
+		if !allValid {
+			continue
+		}
+		for i := uint(0); i < n; i++ {
+			got, err := KeyAt(a, b, i, n)
+			assert.Nil(err, "a=%s b=%s n=%d i=%d", a, b, n, i)
+			assert.Equal(want[i], got, "a=%s b=%s n=%d i=%d", a, b, n, i)
+
+			idx, err := IndexOf(a, b, got, n)
+			assert.Nil(err, "a=%s b=%s n=%d i=%d", a, b, n, i)
+			assert.Equal(i, idx, "a=%s b=%s n=%d i=%d", a, b, n, i)
+		}
+	}
+}
+
+func TestKeyAtErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := KeyAt("a1", "a0", 0, 5)
+	assert.NotNil(err)
+
+	_, err = KeyAt("a0", "a1", 5, 5)
+	assert.NotNil(err)
+
+	_, err = IndexOf("a0", "a1", "a1Z", 5)
+	assert.NotNil(err)
+}
+
+// TestKeyAtEmptySentinelsAreFast guards against regressing to the
+// NKeysBetween(a, b, n) fallback for the common case where a and/or b is
+// empty (appending to the end of a list, or populating one from scratch):
+// that fallback is O(n), which at n=2,000,000 is hundreds of milliseconds,
+// while the direct arithmetic path is microseconds.
+func TestKeyAtEmptySentinelsAreFast(t *testing.T) {
+	assert := assert.New(t)
+	n := uint(2000000)
+
+	for _, c := range []struct{ a, b string }{
+		{"", ""},
+		{"", "a5"},
+		{"a5", ""},
+	} {
+		start := time.Now()
+		_, err := KeyAt(c.a, c.b, n-1, n)
+		assert.Nil(err)
+		assert.True(time.Since(start) < 50*time.Millisecond)
+	}
+}